@@ -0,0 +1,84 @@
+/*
+Copyright 2019 The etcdadm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+*/
+
+package apis
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	"sigs.k8s.io/yaml"
+)
+
+// TenantSpec describes the permissions to grant a tenant's role, loaded from a --spec-file passed to `tenant
+// create`. It replaces the default single readwrite-on-prefix permission with an arbitrary set of key ranges.
+type TenantSpec struct {
+	// Permissions are the key ranges granted to the tenant's role. At least one must be specified.
+	Permissions []PermissionSpec `json:"permissions"`
+}
+
+// PermissionSpec describes a single etcd key range permission.
+type PermissionSpec struct {
+	// Type is one of "read", "write", or "readwrite".
+	Type string `json:"type"`
+
+	// Key is the start of the key range to grant access to.
+	Key string `json:"key"`
+
+	// RangeEnd is the end of the key range to grant access to. Ignored when Prefix is true.
+	RangeEnd string `json:"rangeEnd,omitempty"`
+
+	// Prefix, if true, grants access to every key with Key as a prefix, and RangeEnd is computed from Key instead
+	// of being read from the spec.
+	Prefix bool `json:"prefix,omitempty"`
+}
+
+// ValidatePermissionType returns an error unless t is one of the permission types a PermissionSpec accepts: "read",
+// "write", or "readwrite".
+func ValidatePermissionType(t string) error {
+	switch t {
+	case "read", "write", "readwrite":
+		return nil
+	default:
+		return fmt.Errorf("invalid permission type '%s': must be read, write, or readwrite", t)
+	}
+}
+
+// LoadTenantSpec reads and parses a TenantSpec from path, which may be either YAML or JSON.
+func LoadTenantSpec(path string) (*TenantSpec, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("[apis] failed to read tenant spec file '%s': %v", path, err)
+	}
+
+	var spec TenantSpec
+	if err := yaml.Unmarshal(data, &spec); err != nil {
+		return nil, fmt.Errorf("[apis] failed to parse tenant spec file '%s': %v", path, err)
+	}
+	if len(spec.Permissions) == 0 {
+		return nil, fmt.Errorf("[apis] tenant spec file '%s' must specify at least one permission", path)
+	}
+	for i, perm := range spec.Permissions {
+		if err := ValidatePermissionType(perm.Type); err != nil {
+			return nil, fmt.Errorf("[apis] tenant spec file '%s' permission %d: %v", path, i, err)
+		}
+		if perm.Key == "" {
+			return nil, fmt.Errorf("[apis] tenant spec file '%s' permission %d must specify a key", path, i)
+		}
+	}
+	return &spec, nil
+}
@@ -0,0 +1,117 @@
+/*
+Copyright 2019 The etcdadm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+*/
+
+// Package apis contains the configuration types shared across etcdadm's
+// commands and packages.
+package apis
+
+// EtcdAdmConfig holds the configuration etcdadm uses to install, configure,
+// and administer an etcd member.
+type EtcdAdmConfig struct {
+	// Version is the etcd version being installed/managed.
+	Version string
+
+	// EtcdctlShellWrapper is the path to the etcdctl.sh wrapper script that
+	// sets up the environment etcdctl needs to talk to the local member.
+	EtcdctlShellWrapper string
+
+	// EtcdctlRootUserPassword is the generated password for etcd's root user,
+	// written to the etcdctl env file so subsequent etcdctl invocations can
+	// authenticate.
+	EtcdctlRootUserPassword string
+
+	// Endpoints are the client URLs etcdadm dials when talking to the local
+	// etcd member over the clientv3 API.
+	Endpoints []string
+
+	// CACertFile, ClientCertFile, and ClientKeyFile are the TLS materials
+	// etcdadm uses to authenticate its own clientv3 connections.
+	CACertFile     string
+	ClientCertFile string
+	ClientKeyFile  string
+
+	// CAKeyFile is the cluster CA's private key, used to sign the client
+	// certificates etcdadm issues for tenants. It is only required on the
+	// host that runs `etcdadm tenant create`/`rotate-cert`.
+	CAKeyFile string
+
+	// CertificatesDir is the directory tenant client cert/key pairs are
+	// written to and read from, one "<name>.crt"/"<name>.key" pair per
+	// tenant.
+	CertificatesDir string
+
+	// ClientCertAuth records that etcd is expected to run with --client-cert-auth=true, so clients can authenticate
+	// purely by their TLS certificate's Common Name instead of a username/password. Setting it makes `tenant
+	// create` issue passwordless users (via UserAddOptions{NoPassword: true}) whose client cert CN equals the
+	// tenant name, and write each tenant's etcdctl.env pointing at that cert/key with no ETCDCTL_USER (see
+	// certs.WriteTenantEtcdctlEnvFile).
+	//
+	// This field only records that intent; nothing in this package set starts the etcd process itself, and there
+	// is no `etcdadm init --client-cert-auth` flag to set this from. That lives in etcdadm's cluster bootstrap
+	// path, which isn't part of this package set and remains open follow-up work. An operator must separately
+	// start etcd with --client-cert-auth=true out-of-band for the passwordless tenants above to actually
+	// authenticate.
+	ClientCertAuth bool
+
+	// PasswordPolicy controls how etcdadm generates passwords for root and
+	// tenant users.
+	PasswordPolicy PasswordPolicy
+}
+
+// PasswordPolicy describes the shape of passwords etcdadm generates for etcd
+// users: how long they are, which character classes they draw from, and the
+// minimum entropy a generated password must have.
+type PasswordPolicy struct {
+	// Length is the number of characters in a generated password.
+	Length int
+
+	// IncludeUpper, IncludeLower, IncludeDigits, and IncludeSymbols select the
+	// character classes a generated password draws from. At least one must
+	// be true.
+	IncludeUpper   bool
+	IncludeLower   bool
+	IncludeDigits  bool
+	IncludeSymbols bool
+
+	// MinEntropyBits is the minimum entropy, in bits, a generated password
+	// must have given Length and the selected character classes.
+	MinEntropyBits float64
+}
+
+// SetDefaults populates cfg with etcdadm's default configuration values.
+func SetDefaults(cfg *EtcdAdmConfig) {
+	if cfg.Version == "" {
+		cfg.Version = "3.4.3"
+	}
+	if cfg.PasswordPolicy.Length == 0 {
+		cfg.PasswordPolicy = PasswordPolicy{
+			Length:         24,
+			IncludeUpper:   true,
+			IncludeLower:   true,
+			IncludeDigits:  true,
+			IncludeSymbols: true,
+			MinEntropyBits: 128,
+		}
+	}
+}
+
+// SetInitDynamicDefaults populates cfg with defaults that can only be
+// computed once etcdadm starts running, such as values derived from the
+// host environment.
+func SetInitDynamicDefaults(cfg *EtcdAdmConfig) error {
+	return nil
+}
@@ -0,0 +1,81 @@
+/*
+Copyright 2019 The etcdadm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+*/
+
+package auth
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+
+	"sigs.k8s.io/etcdadm/apis"
+)
+
+const (
+	upperChars  = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	lowerChars  = "abcdefghijklmnopqrstuvwxyz"
+	digitChars  = "0123456789"
+	symbolChars = "!@#$%^&*()-_=+[]{}"
+)
+
+// randomPassword generates a cryptographically secure password matching policy: policy.Length characters drawn
+// uniformly from the union of the character classes policy enables, rejecting policies whose resulting passwords
+// would fall short of policy.MinEntropyBits.
+func randomPassword(policy apis.PasswordPolicy) (string, error) {
+	var charset string
+	if policy.IncludeUpper {
+		charset += upperChars
+	}
+	if policy.IncludeLower {
+		charset += lowerChars
+	}
+	if policy.IncludeDigits {
+		charset += digitChars
+	}
+	if policy.IncludeSymbols {
+		charset += symbolChars
+	}
+	if charset == "" {
+		return "", fmt.Errorf("[auth] password policy must enable at least one character class")
+	}
+	if policy.Length <= 0 {
+		return "", fmt.Errorf("[auth] password policy length must be greater than 0")
+	}
+
+	entropyBits := float64(policy.Length) * math.Log2(float64(len(charset)))
+	if entropyBits < policy.MinEntropyBits {
+		return "", fmt.Errorf("[auth] password policy produces %.1f bits of entropy, below the required minimum of %.1f", entropyBits, policy.MinEntropyBits)
+	}
+
+	var b strings.Builder
+	max := big.NewInt(int64(len(charset)))
+	for i := 0; i < policy.Length; i++ {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", fmt.Errorf("[auth] failed to generate random password: %v", err)
+		}
+		b.WriteByte(charset[n.Int64()])
+	}
+
+	password := b.String()
+	if password == "" {
+		return "", fmt.Errorf("[auth] generated password is invalid for empty password")
+	}
+	return password, nil
+}
@@ -0,0 +1,336 @@
+/*
+Copyright 2019 The etcdadm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+*/
+
+package auth
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"go.etcd.io/etcd/clientv3"
+	"go.etcd.io/etcd/etcdserver/api/v3rpc/rpctypes"
+
+	"sigs.k8s.io/etcdadm/apis"
+	"sigs.k8s.io/etcdadm/certs"
+)
+
+// CreateTenant creates a user "name" according to official etcd documentation, and then assigns it a role granted
+// the permissions in spec. If the tenant already exists, its role's permissions are replaced with those in spec
+// rather than added to: calling CreateTenant again with a different spec (or none) is how a tenant's permissions
+// are updated. When spec is nil, the role is granted the default single permission: readwrite access to the prefix
+// "/name/".
+// When cfg.ClientCertAuth or noPassword is set, the user is created without a password so it can only authenticate
+// via the Common Name of the client certificate issued below, which is guaranteed to equal name, and an
+// etcdctl.env pointing at that cert/key (with no ETCDCTL_USER) is written alongside it. Otherwise, password is
+// used as the user's password if non-empty, or a password matching cfg.PasswordPolicy is generated. An existing
+// user is kept as it is: its password, or lack of one, is not changed. Passing a
+// non-empty password together with cfg.ClientCertAuth or noPassword is an error, since the password would otherwise
+// be silently discarded in favor of passwordless authentication.
+func CreateTenant(cfg *apis.EtcdAdmConfig, name string, password string, noPassword bool, identityURIs []string, spec *apis.TenantSpec) error {
+	noPassword = cfg.ClientCertAuth || noPassword
+	if noPassword && password != "" {
+		return fmt.Errorf("[auth] tenant '%s': a password was supplied, but cfg.ClientCertAuth or --no-password forces passwordless authentication for this tenant and would silently discard it", name)
+	}
+
+	client, err := newClientV3(cfg)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	perms := defaultTenantPermissions(name)
+	if spec != nil {
+		perms = spec.Permissions
+	}
+
+	if err := createUserAndRole(client, cfg, name, password, noPassword, perms); err != nil {
+		return err
+	}
+	if err := certs.CreateTenantClientCertAndKeyFiles(cfg, name, identityURIs); err != nil {
+		return err
+	}
+	if noPassword {
+		if err := certs.WriteTenantEtcdctlEnvFile(cfg, name); err != nil {
+			return err
+		}
+	} else if err := certs.DeleteTenantEtcdctlEnvFile(cfg, name); err != nil {
+		// Removes any etcdctl.env left over from an earlier passwordless creation of this tenant. Recreating a
+		// tenant does not change an existing user's password setting: a user first created passwordless stays
+		// passwordless.
+		return err
+	}
+	return nil
+}
+
+// defaultTenantPermissions is the permission set CreateTenant grants when no TenantSpec is given: readwrite access
+// to the prefix "/name/".
+func defaultTenantPermissions(name string) []apis.PermissionSpec {
+	return []apis.PermissionSpec{
+		{Type: "readwrite", Key: fmt.Sprintf("/%s/", name), Prefix: true},
+	}
+}
+
+// tenantMarkerKey is the sentinel single-key readonly permission createUserAndRole always grants a tenant's role,
+// in addition to whatever permissions its TenantSpec asks for. A user and role sharing a name isn't on its own
+// evidence of a tenant: etcd's own "root" user/role do, and an operator could hand-roll an "admin" user/role the
+// same way. ListTenants and DeleteTenant check for this marker instead, so they only ever treat roles CreateTenant
+// itself granted as tenants.
+func tenantMarkerKey(name string) string {
+	return "/etcdadm/tenants/" + name
+}
+
+// isTenantRole reports whether role carries the tenantMarkerKey permission for name, identifying it as a role
+// CreateTenant manages rather than an unrelated same-named user/role pair.
+func isTenantRole(role *clientv3.AuthRoleGetResponse, name string) bool {
+	marker := tenantMarkerKey(name)
+	for _, perm := range role.Perm {
+		if string(perm.Key) == marker && len(perm.RangeEnd) == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// ListTenants returns the names of tenants previously created by CreateTenant: users with a role of the same name
+// that carries the tenantMarkerKey permission. Matching on the marker rather than on name alone means a cluster's
+// "root" user/role, or any other same-named user/role pair an operator created directly, is never reported as a
+// tenant.
+func ListTenants(cfg *apis.EtcdAdmConfig) ([]string, error) {
+	client, err := newClientV3(cfg)
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), dialTimeout)
+	defer cancel()
+
+	users, err := client.Auth.UserList(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("[auth] failed to list users: %v", err)
+	}
+
+	roles, err := client.Auth.RoleList(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("[auth] failed to list roles: %v", err)
+	}
+	roleSet := make(map[string]bool, len(roles.Roles))
+	for _, role := range roles.Roles {
+		roleSet[role] = true
+	}
+
+	var tenants []string
+	for _, user := range users.Users {
+		if !roleSet[user] {
+			continue
+		}
+		role, err := client.Auth.RoleGet(ctx, user)
+		if err != nil {
+			return nil, fmt.Errorf("[auth] failed to get role '%s': %v", user, err)
+		}
+		if isTenantRole(role, user) {
+			tenants = append(tenants, user)
+		}
+	}
+	return tenants, nil
+}
+
+// DeleteTenant revokes the tenant's role, deletes the user and role, and removes the tenant's client cert, key,
+// and etcdctl.env files. Each step is idempotent: deleting a tenant that doesn't exist, or only partially exists
+// because a previous delete failed partway through, is treated as success so the operation can be retried. If
+// name's role exists but lacks the tenantMarkerKey permission, it is refused rather than deleted: that role
+// belongs to something other than a tenant CreateTenant made, e.g. a cluster's own "root" user/role.
+func DeleteTenant(cfg *apis.EtcdAdmConfig, name string) error {
+	client, err := newClientV3(cfg)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), dialTimeout)
+	defer cancel()
+
+	switch role, err := client.Auth.RoleGet(ctx, name); {
+	case err == rpctypes.ErrRoleNotFound:
+		// No role to verify; proceed with idempotent cleanup of whatever else is left.
+	case err != nil:
+		return fmt.Errorf("[auth] failed to get role '%s': %v", name, err)
+	case !isTenantRole(role, name):
+		return fmt.Errorf("[auth] refusing to delete '%s': its role is not one CreateTenant manages (missing tenant marker permission)", name)
+	}
+
+	if _, err := client.Auth.UserRevokeRole(ctx, name, name); err != nil &&
+		err != rpctypes.ErrUserNotFound && err != rpctypes.ErrRoleNotGranted {
+		return fmt.Errorf("[auth] failed to revoke role '%s' from user '%s': %v", name, name, err)
+	}
+
+	if _, err := client.Auth.UserDelete(ctx, name); err != nil && err != rpctypes.ErrUserNotFound {
+		return fmt.Errorf("[auth] failed to delete user '%s': %v", name, err)
+	}
+
+	if _, err := client.Auth.RoleDelete(ctx, name); err != nil && err != rpctypes.ErrRoleNotFound {
+		return fmt.Errorf("[auth] failed to delete role '%s': %v", name, err)
+	}
+
+	if err := certs.DeleteTenantClientCertAndKeyFiles(cfg, name); err != nil {
+		return err
+	}
+	if err := certs.DeleteTenantEtcdctlEnvFile(cfg, name); err != nil {
+		return err
+	}
+
+	fmt.Printf("[auth] tenant '%s' deleted\n", name)
+	return nil
+}
+
+// RotateTenantCert regenerates the tenant's client cert and key with the same Common Name and an updated NotAfter,
+// without touching the tenant's user, role, or permissions.
+func RotateTenantCert(cfg *apis.EtcdAdmConfig, name string, identityURIs []string) error {
+	client, err := newClientV3(cfg)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), dialTimeout)
+	defer cancel()
+
+	if _, err := client.Auth.UserGet(ctx, name); err != nil {
+		return fmt.Errorf("[auth] tenant '%s' does not have a user to rotate a cert for: %v", name, err)
+	}
+
+	if err := certs.CreateTenantClientCertAndKeyFiles(cfg, name, identityURIs); err != nil {
+		return err
+	}
+	fmt.Printf("[auth] tenant '%s' client cert rotated\n", name)
+	return nil
+}
+
+// createUserAndRole is functionally equivalent to the following commands:
+// `etcdctl user add <name>`
+// `etcdctl role add <name>`
+// `etcdctl role grant-permission <name> [--prefix=true] <type> <key> [<range_end>]` (once per entry in perms)
+// `etcdctl role grant-permission <name> read <tenantMarkerKey>`
+// `etcdctl user grant-role <name> <name>`
+// Each step is idempotent: if the user, role, or grant already exists, it is treated as success.
+// When noPassword is true, the user is created with UserAddOptions{NoPassword: true} so it can only authenticate
+// via TLS Common Name (requires etcd to be running with --client-cert-auth=true). Otherwise, password is used as
+// the user's password if non-empty, or a password matching cfg.PasswordPolicy is generated.
+func createUserAndRole(client *clientv3.Client, cfg *apis.EtcdAdmConfig, name string, password string, noPassword bool, perms []apis.PermissionSpec) error {
+	// FIXME: surely there's a better way to validate this?
+	if strings.Contains(name, "/") {
+		return fmt.Errorf("[auth] invalid value for --name: '%s' cannot contain / (try using a DNS-compliant value)", name)
+	}
+	if len(perms) == 0 {
+		return fmt.Errorf("[auth] tenant '%s' must be granted at least one permission", name)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), dialTimeout)
+	defer cancel()
+
+	var addErr error
+	switch {
+	case noPassword:
+		_, addErr = client.Auth.UserAddWithOptions(ctx, name, "", &clientv3.UserAddOptions{NoPassword: true})
+	case password != "":
+		_, addErr = client.Auth.UserAdd(ctx, name, password)
+	default:
+		generated, err := randomPassword(cfg.PasswordPolicy)
+		if err != nil {
+			return err
+		}
+		_, addErr = client.Auth.UserAdd(ctx, name, generated)
+	}
+	if addErr != nil && addErr != rpctypes.ErrUserAlreadyExist {
+		return fmt.Errorf("[auth] failed to add user '%s': %v", name, addErr)
+	}
+
+	if _, err := client.Auth.RoleAdd(ctx, name); err != nil && err != rpctypes.ErrRoleAlreadyExist {
+		return fmt.Errorf("[auth] failed to add role '%s': %v", name, err)
+	}
+
+	// Revoke any permissions already granted to the role so that recreating a tenant with a different set of
+	// permissions (e.g. a new --spec-file) replaces its access rather than adding to it.
+	existingRole, err := client.Auth.RoleGet(ctx, name)
+	if err != nil {
+		return fmt.Errorf("[auth] failed to get role '%s': %v", name, err)
+	}
+	for _, existingPerm := range existingRole.Perm {
+		if _, err := client.Auth.RoleRevokePermission(ctx, name, string(existingPerm.Key), string(existingPerm.RangeEnd)); err != nil {
+			return fmt.Errorf("[auth] failed to revoke existing permission on '%s' from role '%s': %v", existingPerm.Key, name, err)
+		}
+	}
+
+	for _, perm := range perms {
+		permType, err := permissionType(perm.Type)
+		if err != nil {
+			return fmt.Errorf("[auth] tenant '%s': %v", name, err)
+		}
+
+		rangeEnd := perm.RangeEnd
+		if perm.Prefix {
+			rangeEnd = prefixRangeEnd(perm.Key)
+		}
+
+		if _, err := client.Auth.RoleGrantPermission(ctx, name, perm.Key, rangeEnd, permType); err != nil {
+			return fmt.Errorf("[auth] failed to grant %s permission on '%s' to role '%s': %v", perm.Type, perm.Key, name, err)
+		}
+	}
+
+	// Grant the tenantMarkerKey permission so ListTenants and DeleteTenant can tell this role apart from any
+	// other same-named user/role pair (e.g. etcd's own "root") that CreateTenant didn't create.
+	if _, err := client.Auth.RoleGrantPermission(ctx, name, tenantMarkerKey(name), "", clientv3.PermRead); err != nil {
+		return fmt.Errorf("[auth] failed to grant tenant marker permission to role '%s': %v", name, err)
+	}
+
+	if _, err := client.Auth.UserGrantRole(ctx, name, name); err != nil {
+		return fmt.Errorf("[auth] failed to grant role '%s' to user '%s': %v", name, name, err)
+	}
+
+	fmt.Printf("[auth] tenant '%s' ready with %d permission(s)\n", name, len(perms))
+	return nil
+}
+
+// permissionType maps a PermissionSpec's Type string to the equivalent clientv3.PermissionType.
+func permissionType(t string) (clientv3.PermissionType, error) {
+	if err := apis.ValidatePermissionType(t); err != nil {
+		return 0, err
+	}
+	switch t {
+	case "read":
+		return clientv3.PermRead, nil
+	case "write":
+		return clientv3.PermWrite, nil
+	default: // "readwrite", the only remaining value ValidatePermissionType accepts
+		return clientv3.PermReadWrite, nil
+	}
+}
+
+// prefixRangeEnd computes the range end for a prefix permission, matching etcdctl's
+// `--prefix=true` behavior of incrementing the last byte of the key.
+func prefixRangeEnd(prefix string) string {
+	end := []byte(prefix)
+	for i := len(end) - 1; i >= 0; i-- {
+		if end[i] < 0xff {
+			end[i]++
+			return string(end[:i+1])
+		}
+	}
+	// prefix is all 0xff bytes: there is no finite range end, so match everything after it.
+	return "\x00"
+}
@@ -0,0 +1,73 @@
+/*
+Copyright 2019 The etcdadm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+*/
+
+package auth
+
+import (
+	"context"
+	"testing"
+
+	"go.etcd.io/etcd/clientv3"
+	"go.etcd.io/etcd/integration"
+
+	"sigs.k8s.io/etcdadm/apis"
+)
+
+// TestCreateUserAndRoleReplacesPermissionsOnRecreate guards against regressing to the pre-fix behavior, where
+// recreating an existing tenant with a different spec accumulated permissions onto its role instead of
+// replacing them.
+func TestCreateUserAndRoleReplacesPermissionsOnRecreate(t *testing.T) {
+	clus := integration.NewClusterV3(t, &integration.ClusterConfig{Size: 1})
+	defer clus.Terminate(t)
+
+	client := clus.RandClient()
+	cfg := &apis.EtcdAdmConfig{}
+	apis.SetDefaults(cfg)
+
+	if err := createUserAndRole(client, cfg, "foo", "", false, defaultTenantPermissions("foo")); err != nil {
+		t.Fatalf("initial createUserAndRole call failed: %v", err)
+	}
+
+	restricted := []apis.PermissionSpec{
+		{Type: "read", Key: "/foo/readonly/", Prefix: true},
+	}
+	if err := createUserAndRole(client, cfg, "foo", "", false, restricted); err != nil {
+		t.Fatalf("recreate createUserAndRole call failed: %v", err)
+	}
+
+	ctx := context.Background()
+	role, err := client.Auth.RoleGet(ctx, "foo")
+	if err != nil {
+		t.Fatalf("RoleGet failed: %v", err)
+	}
+	// The new readonly permission plus the tenantMarkerKey permission createUserAndRole always grants.
+	if len(role.Perm) != 2 {
+		t.Fatalf("expected recreating tenant 'foo' with a new spec to replace its permissions, got %d permissions", len(role.Perm))
+	}
+	if !isTenantRole(role, "foo") {
+		t.Fatalf("expected role 'foo' to still carry the tenant marker permission after recreate")
+	}
+	var sawReadonly bool
+	for _, perm := range role.Perm {
+		if string(perm.Key) == "/foo/readonly/" && perm.PermType == clientv3.PermRead {
+			sawReadonly = true
+		}
+	}
+	if !sawReadonly {
+		t.Fatalf("expected role 'foo' to have the new readonly permission, got %+v", role.Perm)
+	}
+}
@@ -0,0 +1,134 @@
+/*
+Copyright 2019 The etcdadm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+*/
+
+package auth
+
+import (
+	"context"
+	"testing"
+
+	"go.etcd.io/etcd/integration"
+
+	"sigs.k8s.io/etcdadm/apis"
+)
+
+func TestCreateUserAndRoleIsIdempotent(t *testing.T) {
+	clus := integration.NewClusterV3(t, &integration.ClusterConfig{Size: 1})
+	defer clus.Terminate(t)
+
+	client := clus.RandClient()
+	cfg := &apis.EtcdAdmConfig{}
+	apis.SetDefaults(cfg)
+	perms := defaultTenantPermissions("foo")
+
+	if err := createUserAndRole(client, cfg, "foo", "", false, perms); err != nil {
+		t.Fatalf("first createUserAndRole call failed: %v", err)
+	}
+	// A second call for the same tenant must succeed even though the user, role, and grant already exist.
+	if err := createUserAndRole(client, cfg, "foo", "", false, perms); err != nil {
+		t.Fatalf("second createUserAndRole call for an existing tenant failed: %v", err)
+	}
+
+	ctx := context.Background()
+	role, err := client.Auth.RoleGet(ctx, "foo")
+	if err != nil {
+		t.Fatalf("RoleGet failed: %v", err)
+	}
+	// defaultTenantPermissions' single permission plus the tenantMarkerKey permission createUserAndRole always grants.
+	if len(role.Perm) != 2 {
+		t.Fatalf("expected role 'foo' to have exactly 2 permissions, got %d", len(role.Perm))
+	}
+	if !isTenantRole(role, "foo") {
+		t.Fatalf("expected role 'foo' to carry the tenant marker permission")
+	}
+}
+
+func TestListTenantsExcludesSameNamedNonTenantRole(t *testing.T) {
+	clus := integration.NewClusterV3(t, &integration.ClusterConfig{Size: 1})
+	defer clus.Terminate(t)
+
+	client := clus.RandClient()
+	cfg := &apis.EtcdAdmConfig{Endpoints: client.Endpoints()}
+	apis.SetDefaults(cfg)
+	ctx := context.Background()
+
+	// A user/role pair sharing a name - like etcd's own "root" user/role, or a hand-rolled "admin" - must never be
+	// reported as a tenant just because the names match; only createUserAndRole's tenant marker does that.
+	if _, err := client.Auth.UserAdd(ctx, "admin", "password"); err != nil {
+		t.Fatalf("UserAdd failed: %v", err)
+	}
+	if _, err := client.Auth.RoleAdd(ctx, "admin"); err != nil {
+		t.Fatalf("RoleAdd failed: %v", err)
+	}
+	if _, err := client.Auth.UserGrantRole(ctx, "admin", "admin"); err != nil {
+		t.Fatalf("UserGrantRole failed: %v", err)
+	}
+
+	if err := createUserAndRole(client, cfg, "foo", "", false, defaultTenantPermissions("foo")); err != nil {
+		t.Fatalf("createUserAndRole failed: %v", err)
+	}
+
+	tenants, err := ListTenants(cfg)
+	if err != nil {
+		t.Fatalf("ListTenants failed: %v", err)
+	}
+	if len(tenants) != 1 || tenants[0] != "foo" {
+		t.Fatalf("expected ListTenants to return only ['foo'], got %v", tenants)
+	}
+}
+
+func TestDeleteTenantRefusesNonTenantRole(t *testing.T) {
+	clus := integration.NewClusterV3(t, &integration.ClusterConfig{Size: 1})
+	defer clus.Terminate(t)
+
+	client := clus.RandClient()
+	cfg := &apis.EtcdAdmConfig{Endpoints: client.Endpoints()}
+	apis.SetDefaults(cfg)
+	ctx := context.Background()
+
+	if _, err := client.Auth.UserAdd(ctx, "root", "password"); err != nil {
+		t.Fatalf("UserAdd failed: %v", err)
+	}
+	if _, err := client.Auth.RoleAdd(ctx, "root"); err != nil {
+		t.Fatalf("RoleAdd failed: %v", err)
+	}
+	if _, err := client.Auth.UserGrantRole(ctx, "root", "root"); err != nil {
+		t.Fatalf("UserGrantRole failed: %v", err)
+	}
+
+	if err := DeleteTenant(cfg, "root"); err == nil {
+		t.Fatal("expected DeleteTenant to refuse deleting a same-named role lacking the tenant marker, got nil")
+	}
+
+	if _, err := client.Auth.UserGet(ctx, "root"); err != nil {
+		t.Fatalf("expected root user to survive the refused delete, UserGet failed: %v", err)
+	}
+}
+
+func TestCreateUserAndRoleRejectsSlashInName(t *testing.T) {
+	clus := integration.NewClusterV3(t, &integration.ClusterConfig{Size: 1})
+	defer clus.Terminate(t)
+
+	client := clus.RandClient()
+	cfg := &apis.EtcdAdmConfig{}
+	apis.SetDefaults(cfg)
+
+	err := createUserAndRole(client, cfg, "foo/bar", "", false, defaultTenantPermissions("foo/bar"))
+	if err == nil {
+		t.Fatal("expected an error for a tenant name containing '/', got nil")
+	}
+}
@@ -0,0 +1,230 @@
+/*
+Copyright 2019 The etcdadm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+*/
+
+// Package certs manages the TLS certificates and keys etcdadm issues for
+// etcd members and tenant clients.
+package certs
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"sigs.k8s.io/etcdadm/apis"
+)
+
+// tenantCertValidity is how long a tenant client certificate is valid for from the moment it is issued, either by
+// `tenant create` or `tenant rotate-cert`.
+const tenantCertValidity = 365 * 24 * time.Hour
+
+// CreateTenantClientCertAndKeyFiles issues a client certificate and key for
+// the tenant "name", signed by the cluster CA, and writes them to disk so
+// they can be distributed to the tenant. The certificate's Common Name is
+// guaranteed to equal name, so the tenant can authenticate with etcd's
+// --client-cert-auth by certificate alone, with no password required.
+// identityURIs, if non-empty, are added to the certificate as SAN URIs so the
+// tenant's identity can carry additional structured information (e.g. a
+// SPIFFE ID) beyond the CN.
+func CreateTenantClientCertAndKeyFiles(cfg *apis.EtcdAdmConfig, name string, identityURIs []string) error {
+	if name == "" {
+		return fmt.Errorf("[certs] tenant name must not be empty")
+	}
+
+	caCert, caKey, err := loadCA(cfg)
+	if err != nil {
+		return err
+	}
+
+	uris := make([]*url.URL, 0, len(identityURIs))
+	for _, raw := range identityURIs {
+		u, err := url.Parse(raw)
+		if err != nil {
+			return fmt.Errorf("[certs] invalid identity URI '%s': %v", raw, err)
+		}
+		uris = append(uris, u)
+	}
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return fmt.Errorf("[certs] failed to generate private key for tenant '%s': %v", name, err)
+	}
+
+	serialNumber, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return fmt.Errorf("[certs] failed to generate serial number for tenant '%s': %v", name, err)
+	}
+
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber: serialNumber,
+		Subject:      pkix.Name{CommonName: name},
+		URIs:         uris,
+		NotBefore:    now,
+		NotAfter:     now.Add(tenantCertValidity),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, caCert, &key.PublicKey, caKey)
+	if err != nil {
+		return fmt.Errorf("[certs] failed to sign certificate for tenant '%s': %v", name, err)
+	}
+
+	certFile, keyFile := tenantCertPaths(cfg, name)
+	if err := writePEMFile(certFile, "CERTIFICATE", der, 0644); err != nil {
+		return err
+	}
+	if err := writePEMFile(keyFile, "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(key), 0600); err != nil {
+		return err
+	}
+	return nil
+}
+
+// DeleteTenantClientCertAndKeyFiles removes the client cert and key files previously issued for the tenant "name".
+// Removing files for a tenant that was never issued one is treated as success.
+func DeleteTenantClientCertAndKeyFiles(cfg *apis.EtcdAdmConfig, name string) error {
+	if name == "" {
+		return fmt.Errorf("[certs] tenant name must not be empty")
+	}
+
+	certFile, keyFile := tenantCertPaths(cfg, name)
+	if err := os.Remove(certFile); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("[certs] failed to remove cert file '%s' for tenant '%s': %v", certFile, name, err)
+	}
+	if err := os.Remove(keyFile); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("[certs] failed to remove key file '%s' for tenant '%s': %v", keyFile, name, err)
+	}
+	return nil
+}
+
+// tenantCertPaths returns the cert and key file paths CreateTenantClientCertAndKeyFiles writes to, and
+// DeleteTenantClientCertAndKeyFiles removes, for the tenant "name".
+func tenantCertPaths(cfg *apis.EtcdAdmConfig, name string) (certFile, keyFile string) {
+	return filepath.Join(cfg.CertificatesDir, name+".crt"), filepath.Join(cfg.CertificatesDir, name+".key")
+}
+
+// tenantEtcdctlEnvPath returns the path WriteTenantEtcdctlEnvFile writes to, and DeleteTenantEtcdctlEnvFile
+// removes, for the tenant "name".
+func tenantEtcdctlEnvPath(cfg *apis.EtcdAdmConfig, name string) string {
+	return filepath.Join(cfg.CertificatesDir, name+".etcdctl.env")
+}
+
+// WriteTenantEtcdctlEnvFile writes an etcdctl.env file for the tenant "name" that points etcdctl at the tenant's
+// own client cert and key instead of a username/password. It's meant for tenants created passwordless (via
+// cfg.ClientCertAuth or --no-password), so it omits ETCDCTL_USER entirely rather than writing one with no
+// password to pair with: those tenants authenticate purely by the cert's Common Name, which requires etcd to be
+// running with --client-cert-auth=true.
+func WriteTenantEtcdctlEnvFile(cfg *apis.EtcdAdmConfig, name string) error {
+	if name == "" {
+		return fmt.Errorf("[certs] tenant name must not be empty")
+	}
+
+	certFile, keyFile := tenantCertPaths(cfg, name)
+	env := fmt.Sprintf(
+		"ETCDCTL_API=3\nETCDCTL_ENDPOINTS=%s\nETCDCTL_CACERT=%s\nETCDCTL_CERT=%s\nETCDCTL_KEY=%s\n",
+		strings.Join(cfg.Endpoints, ","), cfg.CACertFile, certFile, keyFile,
+	)
+
+	path := tenantEtcdctlEnvPath(cfg, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("[certs] failed to create directory for '%s': %v", path, err)
+	}
+	if err := ioutil.WriteFile(path, []byte(env), 0644); err != nil {
+		return fmt.Errorf("[certs] failed to write etcdctl env file '%s': %v", path, err)
+	}
+	return nil
+}
+
+// DeleteTenantEtcdctlEnvFile removes the etcdctl env file previously written for the tenant "name" by
+// WriteTenantEtcdctlEnvFile. Removing it for a tenant that never had one written (e.g. a password-based tenant)
+// is treated as success.
+func DeleteTenantEtcdctlEnvFile(cfg *apis.EtcdAdmConfig, name string) error {
+	if name == "" {
+		return fmt.Errorf("[certs] tenant name must not be empty")
+	}
+
+	path := tenantEtcdctlEnvPath(cfg, name)
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("[certs] failed to remove etcdctl env file '%s' for tenant '%s': %v", path, name, err)
+	}
+	return nil
+}
+
+// loadCA reads and parses the cluster CA certificate and private key from cfg.CACertFile and cfg.CAKeyFile.
+func loadCA(cfg *apis.EtcdAdmConfig) (*x509.Certificate, crypto.Signer, error) {
+	certPEM, err := ioutil.ReadFile(cfg.CACertFile)
+	if err != nil {
+		return nil, nil, fmt.Errorf("[certs] failed to read CA cert file '%s': %v", cfg.CACertFile, err)
+	}
+	certBlock, _ := pem.Decode(certPEM)
+	if certBlock == nil {
+		return nil, nil, fmt.Errorf("[certs] failed to decode PEM block from CA cert file '%s'", cfg.CACertFile)
+	}
+	caCert, err := x509.ParseCertificate(certBlock.Bytes)
+	if err != nil {
+		return nil, nil, fmt.Errorf("[certs] failed to parse CA cert file '%s': %v", cfg.CACertFile, err)
+	}
+
+	keyPEM, err := ioutil.ReadFile(cfg.CAKeyFile)
+	if err != nil {
+		return nil, nil, fmt.Errorf("[certs] failed to read CA key file '%s': %v", cfg.CAKeyFile, err)
+	}
+	keyBlock, _ := pem.Decode(keyPEM)
+	if keyBlock == nil {
+		return nil, nil, fmt.Errorf("[certs] failed to decode PEM block from CA key file '%s'", cfg.CAKeyFile)
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(keyBlock.Bytes); err == nil {
+		return caCert, key, nil
+	}
+	key, err := x509.ParsePKCS8PrivateKey(keyBlock.Bytes)
+	if err != nil {
+		return nil, nil, fmt.Errorf("[certs] failed to parse CA key file '%s': %v", cfg.CAKeyFile, err)
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, nil, fmt.Errorf("[certs] CA key file '%s' does not contain a signing key", cfg.CAKeyFile)
+	}
+	return caCert, signer, nil
+}
+
+// writePEMFile PEM-encodes der under blockType and writes it to path with the given file mode.
+func writePEMFile(path, blockType string, der []byte, mode os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("[certs] failed to create directory for '%s': %v", path, err)
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode)
+	if err != nil {
+		return fmt.Errorf("[certs] failed to open '%s' for writing: %v", path, err)
+	}
+	defer f.Close()
+	if err := pem.Encode(f, &pem.Block{Type: blockType, Bytes: der}); err != nil {
+		return fmt.Errorf("[certs] failed to write PEM data to '%s': %v", path, err)
+	}
+	return nil
+}
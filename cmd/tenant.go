@@ -17,39 +17,14 @@ limitations under the License.
 package cmd
 
 import (
-	log "sigs.k8s.io/etcdadm/pkg/logrus"
-
 	"github.com/spf13/cobra"
-
-	"sigs.k8s.io/etcdadm/apis"
-	"sigs.k8s.io/etcdadm/auth"
 )
 
-
 var tenantCmd = &cobra.Command{
 	Use:   "tenant",
-	Short: "Creates a user and assigns it full read/write access to a specified prefix that will be created if it doesn't exist",
-	Run: func(cmd *cobra.Command, args []string) {
-		apis.SetDefaults(&etcdAdmConfig)
-		if err := apis.SetInitDynamicDefaults(&etcdAdmConfig); err != nil {
-			log.Fatalf("[defaults] Error: %s", err)
-		}
-
-		name, err := cmd.Flags().GetString("name")
-		if err != nil {
-			log.Fatalf("Error parsing option value for name")
-		}
-
-		if err = auth.CreateTenant(&etcdAdmConfig, name); err != nil {
-			log.Fatalf("[tenant] Error: %s", err)
-		}
-	},
+	Short: "Manages tenants: users scoped to a readwrite prefix of etcd's keyspace, or to the key ranges of a --spec-file",
 }
 
 func init() {
 	rootCmd.AddCommand(tenantCmd)
-	// TODO: Make --name flag required
-	tenantCmd.Flags().String("name", "", "Specify name to be used as: client cert Common Name(CN), user, role, and prefix. The user is given readwrite access to the prefix of the same name. The prefix is created at the root of etcd for now.")
-	tenantCmd.MarkFlagRequired("name")
-	// tenantCmd.Flags().String("prefix", "", "The etcd prefix path to grant full read/write access to user")
 }
@@ -0,0 +1,118 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	log "sigs.k8s.io/etcdadm/pkg/logrus"
+
+	"github.com/spf13/cobra"
+
+	"sigs.k8s.io/etcdadm/apis"
+	"sigs.k8s.io/etcdadm/auth"
+)
+
+var tenantCreateCmd = &cobra.Command{
+	Use:   "create",
+	Short: "Creates a user and assigns it full read/write access to the prefix of the same name, or the permissions given by --spec-file",
+	Run: func(cmd *cobra.Command, args []string) {
+		apis.SetDefaults(&etcdAdmConfig)
+		if err := apis.SetInitDynamicDefaults(&etcdAdmConfig); err != nil {
+			log.Fatalf("[defaults] Error: %s", err)
+		}
+
+		name, err := cmd.Flags().GetString("name")
+		if err != nil {
+			log.Fatalf("Error parsing option value for name")
+		}
+
+		noPassword, err := cmd.Flags().GetBool("no-password")
+		if err != nil {
+			log.Fatalf("Error parsing option value for no-password")
+		}
+
+		identityURIs, err := cmd.Flags().GetStringSlice("identity-uri")
+		if err != nil {
+			log.Fatalf("Error parsing option value for identity-uri")
+		}
+
+		passwordStdin, err := cmd.Flags().GetBool("password-stdin")
+		if err != nil {
+			log.Fatalf("Error parsing option value for password-stdin")
+		}
+
+		if passwordStdin && noPassword {
+			log.Fatalf("[tenant] Error: --password-stdin and --no-password are mutually exclusive")
+		}
+
+		var password string
+		if passwordStdin {
+			password, err = readPasswordFromStdin()
+			if err != nil {
+				log.Fatalf("[tenant] Error: %s", err)
+			}
+		}
+
+		specFile, err := cmd.Flags().GetString("spec-file")
+		if err != nil {
+			log.Fatalf("Error parsing option value for spec-file")
+		}
+
+		var spec *apis.TenantSpec
+		if specFile != "" {
+			spec, err = apis.LoadTenantSpec(specFile)
+			if err != nil {
+				log.Fatalf("[tenant] Error: %s", err)
+			}
+		}
+
+		if err = auth.CreateTenant(&etcdAdmConfig, name, password, noPassword, identityURIs, spec); err != nil {
+			log.Fatalf("[tenant] Error: %s", err)
+		}
+	},
+}
+
+// readPasswordFromStdin reads a single line from stdin to use as a tenant's password, rejecting an empty password
+// to mirror etcd's own "invalid for empty password" behavior.
+func readPasswordFromStdin() (string, error) {
+	scanner := bufio.NewScanner(os.Stdin)
+	scanner.Scan()
+	if err := scanner.Err(); err != nil {
+		return "", fmt.Errorf("[tenant] failed to read password from stdin: %v", err)
+	}
+	password := strings.TrimSpace(scanner.Text())
+	if password == "" {
+		return "", fmt.Errorf("[tenant] invalid for empty password")
+	}
+	return password, nil
+}
+
+func init() {
+	tenantCmd.AddCommand(tenantCreateCmd)
+	// TODO: Make --name flag required
+	tenantCreateCmd.Flags().String("name", "", "Specify name to be used as: client cert Common Name(CN), user, role, and prefix. Unless --spec-file is given, the user is given readwrite access to the prefix of the same name, at the root of etcd.")
+	tenantCreateCmd.MarkFlagRequired("name")
+	// tenantCreateCmd.Flags().String("prefix", "", "The etcd prefix path to grant full read/write access to user")
+	tenantCreateCmd.Flags().Bool("no-password", false, "Create the tenant user with no password, so it can only authenticate by the Common Name of its client certificate (requires etcd running with --client-cert-auth=true)")
+	tenantCreateCmd.Flags().StringSlice("identity-uri", nil, "Additional SAN URIs to embed in the tenant's client certificate alongside its Common Name")
+	tenantCreateCmd.Flags().Bool("password-stdin", false, "Read the tenant user's password from stdin instead of generating one")
+	tenantCreateCmd.Flags().String("spec-file", "", "Path to a YAML or JSON file describing the tenant's permissions per apis.TenantSpec, overriding the default single readwrite-on-prefix permission")
+}
@@ -0,0 +1,52 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+
+	log "sigs.k8s.io/etcdadm/pkg/logrus"
+
+	"github.com/spf13/cobra"
+
+	"sigs.k8s.io/etcdadm/apis"
+	"sigs.k8s.io/etcdadm/auth"
+)
+
+var tenantListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "Lists the tenants previously created with `tenant create`",
+	Run: func(cmd *cobra.Command, args []string) {
+		apis.SetDefaults(&etcdAdmConfig)
+		if err := apis.SetInitDynamicDefaults(&etcdAdmConfig); err != nil {
+			log.Fatalf("[defaults] Error: %s", err)
+		}
+
+		tenants, err := auth.ListTenants(&etcdAdmConfig)
+		if err != nil {
+			log.Fatalf("[tenant] Error: %s", err)
+		}
+
+		for _, tenant := range tenants {
+			fmt.Println(tenant)
+		}
+	},
+}
+
+func init() {
+	tenantCmd.AddCommand(tenantListCmd)
+}
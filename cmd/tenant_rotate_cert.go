@@ -0,0 +1,52 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	log "sigs.k8s.io/etcdadm/pkg/logrus"
+
+	"github.com/spf13/cobra"
+
+	"sigs.k8s.io/etcdadm/apis"
+	"sigs.k8s.io/etcdadm/auth"
+)
+
+var tenantRotateCertCmd = &cobra.Command{
+	Use:   "rotate-cert <name>",
+	Short: "Regenerates a tenant's client cert and key, preserving its user, role, and permissions",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		apis.SetDefaults(&etcdAdmConfig)
+		if err := apis.SetInitDynamicDefaults(&etcdAdmConfig); err != nil {
+			log.Fatalf("[defaults] Error: %s", err)
+		}
+
+		identityURIs, err := cmd.Flags().GetStringSlice("identity-uri")
+		if err != nil {
+			log.Fatalf("Error parsing option value for identity-uri")
+		}
+
+		if err := auth.RotateTenantCert(&etcdAdmConfig, args[0], identityURIs); err != nil {
+			log.Fatalf("[tenant] Error: %s", err)
+		}
+	},
+}
+
+func init() {
+	tenantCmd.AddCommand(tenantRotateCertCmd)
+	tenantRotateCertCmd.Flags().StringSlice("identity-uri", nil, "Additional SAN URIs to embed in the tenant's client certificate alongside its Common Name")
+}